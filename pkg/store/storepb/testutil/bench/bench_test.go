@@ -0,0 +1,95 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package bench
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/thanos-io/thanos/pkg/objstore/client"
+	"github.com/thanos-io/thanos/pkg/store"
+)
+
+// bucketConfigFile points at a YAML pkg/objstore/client.BucketConfig describing
+// the bucket to benchmark against, e.g. the one backing a running e2ebench
+// topology or a containerized store started by e2e. It is intentionally a
+// flag (rather than hard-coded) so the same binary can be pointed at
+// whichever bucket a PR wants to regress-check.
+var bucketConfigFile = flag.String("test.bucket-config-file", "", "path to a pkg/objstore/client.BucketConfig YAML file for BenchmarkStoreSeries")
+
+// bucketMaxTime anchors StandardSeriesCases' query windows to the bucket
+// corpus's own end time rather than time.Now(), since the corpus (e.g.
+// test/e2ebench's store1Data/bkt1) is generated once with a fixed end time
+// and reused across runs.
+var bucketMaxTime = flag.String("test.bucket-max-time", "", "RFC3339 timestamp of the -test.bucket-config-file corpus's end time, used to anchor StandardSeriesCases")
+
+// BenchmarkStoreSeries replays StandardSeriesCases (anchored to
+// -test.bucket-max-time) against an in-process store.BucketStore backed by
+// the bucket described by -test.bucket-config-file, recording wall time,
+// allocated bytes, chunks fetched and index-cache hit ratios per case, plus a
+// JSON report for regression comparisons. Skipped if either flag is unset.
+func BenchmarkStoreSeries(b *testing.B) {
+	if *bucketConfigFile == "" {
+		b.Skip("-test.bucket-config-file not set")
+	}
+	if *bucketMaxTime == "" {
+		b.Skip("-test.bucket-max-time not set")
+	}
+	maxTime, err := time.Parse(time.RFC3339, *bucketMaxTime)
+	if err != nil {
+		b.Fatalf("parse -test.bucket-max-time: %v", err)
+	}
+
+	confContentYaml, err := os.ReadFile(*bucketConfigFile)
+	if err != nil {
+		b.Fatalf("read bucket config: %v", err)
+	}
+
+	logger := log.NewNopLogger()
+	bkt, err := client.NewBucket(logger, confContentYaml, "bench-store-series")
+	if err != nil {
+		b.Fatalf("create bucket client: %v", err)
+	}
+	defer func() { _ = bkt.Close() }()
+
+	// reg is handed to the BucketStore so CacheStatsFromRegistry can read its
+	// postings/series index-cache hit-ratio metrics back out after each case.
+	reg := prometheus.NewRegistry()
+
+	ctx := context.Background()
+	bs, err := store.NewBucketStore(
+		logger,
+		reg,
+		bkt,
+		b.TempDir(),
+		nil,
+		20,
+		store.NewChunksLimiterFactory(0),
+		store.NewSeriesLimiterFactory(0),
+		store.NewBytesLimiterFactory(0),
+		store.NewGapBasedPartitioner(store.PartitionerMaxGapSize),
+		false,
+		20,
+		nil,
+		false,
+		store.DefaultPostingOffsetInMemorySampling,
+		true,
+		false,
+		0,
+	)
+	if err != nil {
+		b.Fatalf("create bucket store: %v", err)
+	}
+	if err := bs.SyncBlocks(ctx); err != nil {
+		b.Fatalf("sync blocks: %v", err)
+	}
+
+	RunSeriesBenchmark(b, NewInProcessSeriesClient(bs), StandardSeriesCases(maxTime), CacheStatsFromRegistry(reg), "store_series_bench.json")
+}
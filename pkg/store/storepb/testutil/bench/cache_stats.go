@@ -0,0 +1,59 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package bench
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// CacheStatsFromRegistry returns a CacheStatsFunc that samples the raw
+// cumulative postings/series cache hit and request counts from the
+// thanos_store_index_cache_{requests,hits}_total counters (labeled by
+// `item_type`) registered on reg, e.g. the registry an in-process
+// *store.BucketStore was constructed with. The counters are cumulative for
+// the life of reg, so callers must diff two samples (see CacheStats.Sub)
+// rather than read a single sample's ratio directly.
+func CacheStatsFromRegistry(reg *prometheus.Registry) CacheStatsFunc {
+	return func() (CacheStats, error) {
+		mfs, err := reg.Gather()
+		if err != nil {
+			return CacheStats{}, err
+		}
+
+		hits := map[string]float64{}
+		reqs := map[string]float64{}
+		for _, mf := range mfs {
+			switch mf.GetName() {
+			case "thanos_store_index_cache_hits_total":
+				sumByItemType(mf, hits)
+			case "thanos_store_index_cache_requests_total":
+				sumByItemType(mf, reqs)
+			}
+		}
+
+		return CacheStats{
+			PostingsHits:     hits["postings"],
+			PostingsRequests: reqs["postings"],
+			SeriesHits:       hits["series"],
+			SeriesRequests:   reqs["series"],
+		}, nil
+	}
+}
+
+// sumByItemType accumulates a counter metric family's values into out, keyed
+// by its lower-cased `item_type` label (e.g. "postings", "series").
+func sumByItemType(mf *dto.MetricFamily, out map[string]float64) {
+	for _, m := range mf.GetMetric() {
+		var itemType string
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "item_type" {
+				itemType = strings.ToLower(l.GetValue())
+			}
+		}
+		out[itemType] += m.GetCounter().GetValue()
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package bench
+
+import (
+	"time"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// StandardSeriesCases returns a small, representative fixture set for
+// BenchmarkStoreSeries, matching the shape used in earlier ad-hoc Thanos
+// block benchmarks: a full-range aggregation query, a narrow instant-query
+// slice, a label-only lookup, and a high-cardinality regex match.
+//
+// BenchmarkStoreSeries points -test.bucket-config-file at a single bucket
+// (e.g. store1Data/bkt1 from test/e2ebench), so the ExpectedSeries values
+// below assume the single 1w, 10k-series, cluster="eu-1" corpus produced by
+// pkg/testutil/e2eutil/blockgen with the "continuous_app_metric" prefix;
+// adjust them if the corpus changes. maxTime must be the corpus's own end
+// time (e.g. -test.bucket-max-time), not time.Now(): the corpus is generated
+// once and reused across runs, so anchoring to wall-clock time would drift
+// the query windows below out of the corpus's fixed range and every case
+// would silently return 0 series.
+func StandardSeriesCases(maxTime time.Time) []SeriesCase {
+	return []SeriesCase{
+		{
+			Name: "full-2w-range-aggregation",
+			Req: &storepb.SeriesRequest{
+				MinTime: timeMs(maxTime, -14*24*time.Hour),
+				MaxTime: timeMs(maxTime, 0),
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_RE, Name: "__name__", Value: "continuous_app_metric.*"},
+				},
+			},
+			ExpectedSeries: 10000,
+		},
+		{
+			Name: "instant-query-15s-slice",
+			Req: &storepb.SeriesRequest{
+				MinTime: timeMs(maxTime, -15*time.Second),
+				MaxTime: timeMs(maxTime, 0),
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_EQ, Name: "__name__", Value: "continuous_app_metric0"},
+				},
+			},
+			ExpectedSeries: 1,
+		},
+		{
+			Name: "skip-chunks-label-lookup",
+			Req: &storepb.SeriesRequest{
+				MinTime: timeMs(maxTime, -14*24*time.Hour),
+				MaxTime: timeMs(maxTime, 0),
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_EQ, Name: "cluster", Value: "eu-1"},
+				},
+				SkipChunks: true,
+			},
+			ExpectedSeries: 10000,
+		},
+		{
+			Name: "high-cardinality-name-regex",
+			Req: &storepb.SeriesRequest{
+				MinTime: timeMs(maxTime, -14*24*time.Hour),
+				MaxTime: timeMs(maxTime, 0),
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_RE, Name: "__name__", Value: "continuous_app_metric9.*"},
+				},
+				MaxResolutionWindow: int64(5 * time.Minute / time.Millisecond),
+			},
+			// continuous_app_metric{9,90-99,900-999,9000-9999}: 1 + 10 + 100 + 1000.
+			ExpectedSeries: 1111,
+		},
+	}
+}
+
+// timeMs returns a millisecond timestamp offset from anchor by d; negative d
+// looks back in time. It exists only to keep the fixture table above
+// readable in terms of durations rather than raw epoch milliseconds.
+func timeMs(anchor time.Time, d time.Duration) int64 {
+	return anchor.Add(d).UnixNano() / int64(time.Millisecond)
+}
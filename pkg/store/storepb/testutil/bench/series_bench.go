@@ -0,0 +1,264 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package bench provides a reusable Store API benchmark harness driven by a
+// table of named storepb.SeriesRequest fixtures. It is meant to be called
+// from a `go test -bench` entrypoint (e.g. BenchmarkStoreSeries) against
+// either an in-process store.BucketStore or a containerized store started by
+// the e2e framework, so that the two are directly comparable.
+//
+// This lives apart from the sibling pkg/store/storepb/testutil package,
+// which hosts the SeriesCase/TestServerSeries correctness-test harness
+// imported by pkg/store/*_test.go; the two packages model a similarly named
+// concept (a "series case") for different purposes and must not collide.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+)
+
+// SeriesCase is a single named Store API query fixture to replay against a
+// store.
+type SeriesCase struct {
+	// Name identifies the case in benchmark sub-test names and the JSON report.
+	Name string
+	// Req is replayed verbatim against the store under test.
+	Req *storepb.SeriesRequest
+	// ExpectedSeries is the series count the case is expected to return; used
+	// as a correctness sanity check, not a performance assertion.
+	ExpectedSeries int
+}
+
+// SeriesClient abstracts over an in-process store.BucketStore and a gRPC
+// store client so the same cases can be replayed against either.
+type SeriesClient interface {
+	Series(ctx context.Context, req *storepb.SeriesRequest) (numSeries, numChunks int, err error)
+}
+
+// grpcSeriesClient replays cases against a real gRPC endpoint, e.g. a store
+// gateway started by the e2e framework.
+type grpcSeriesClient struct {
+	c storepb.StoreClient
+}
+
+// NewGRPCSeriesClient wraps a gRPC client connection to a running store
+// (store gateway, sidecar, receiver, ...) for use with RunSeriesBenchmark.
+func NewGRPCSeriesClient(cc *grpc.ClientConn) SeriesClient {
+	return &grpcSeriesClient{c: storepb.NewStoreClient(cc)}
+}
+
+func (g *grpcSeriesClient) Series(ctx context.Context, req *storepb.SeriesRequest) (int, int, error) {
+	stream, err := g.c.Series(ctx, req)
+	if err != nil {
+		return 0, 0, err
+	}
+	var numSeries, numChunks int
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return numSeries, numChunks, err
+		}
+		if s := resp.GetSeries(); s != nil {
+			numSeries++
+			numChunks += len(s.Chunks)
+		}
+	}
+	return numSeries, numChunks, nil
+}
+
+// inProcessSeriesClient replays cases directly against a storepb.StoreServer
+// implementation (e.g. *store.BucketStore) without going over the network.
+type inProcessSeriesClient struct {
+	srv storepb.StoreServer
+}
+
+// NewInProcessSeriesClient wraps a storepb.StoreServer (such as
+// *store.BucketStore) for use with RunSeriesBenchmark without a gRPC hop.
+func NewInProcessSeriesClient(srv storepb.StoreServer) SeriesClient {
+	return &inProcessSeriesClient{srv: srv}
+}
+
+func (ip *inProcessSeriesClient) Series(ctx context.Context, req *storepb.SeriesRequest) (int, int, error) {
+	collector := &seriesCollector{ctx: ctx}
+	if err := ip.srv.Series(req, collector); err != nil {
+		return 0, 0, err
+	}
+	return collector.numSeries, collector.numChunks, nil
+}
+
+// seriesCollector implements storepb.Store_SeriesServer by simply counting
+// what would have been streamed back to the client.
+type seriesCollector struct {
+	grpc.ServerStream
+	ctx                  context.Context
+	numSeries, numChunks int
+}
+
+func (s *seriesCollector) Context() context.Context { return s.ctx }
+
+func (s *seriesCollector) Send(r *storepb.SeriesResponse) error {
+	if series := r.GetSeries(); series != nil {
+		s.numSeries++
+		s.numChunks += len(series.Chunks)
+	}
+	return nil
+}
+
+// CacheStats captures raw, cumulative postings/series cache hit and request
+// counts sampled from the store under test. See CacheStatsFromRegistry for
+// the in-process implementation backed by an in-process store.BucketStore's
+// metrics registry. Counts are cumulative for the life of the store, so a
+// single sample's ratio is meaningless; RunSeriesBenchmark diffs two samples
+// via Sub before computing a ratio, so that cache activity from earlier
+// cases doesn't dilute a later case's reported hit ratio.
+type CacheStats struct {
+	PostingsHits, PostingsRequests float64
+	SeriesHits, SeriesRequests     float64
+}
+
+// Sub returns the per-case counts observed between before and s.
+func (s CacheStats) Sub(before CacheStats) CacheStats {
+	return CacheStats{
+		PostingsHits:     s.PostingsHits - before.PostingsHits,
+		PostingsRequests: s.PostingsRequests - before.PostingsRequests,
+		SeriesHits:       s.SeriesHits - before.SeriesHits,
+		SeriesRequests:   s.SeriesRequests - before.SeriesRequests,
+	}
+}
+
+// CacheHitRatios is the postings/series cache hit ratio observed during a
+// single SeriesCase, derived from a CacheStats delta.
+type CacheHitRatios struct {
+	PostingsCacheHitRatio float64 `json:"postings_cache_hit_ratio"`
+	SeriesCacheHitRatio   float64 `json:"series_cache_hit_ratio"`
+}
+
+func ratioOf(delta CacheStats) CacheHitRatios {
+	ratio := func(hits, reqs float64) float64 {
+		if reqs == 0 {
+			return 0
+		}
+		return hits / reqs
+	}
+	return CacheHitRatios{
+		PostingsCacheHitRatio: ratio(delta.PostingsHits, delta.PostingsRequests),
+		SeriesCacheHitRatio:   ratio(delta.SeriesHits, delta.SeriesRequests),
+	}
+}
+
+// CaseResult is the outcome of replaying a single SeriesCase.
+type CaseResult struct {
+	Name          string         `json:"name"`
+	Duration      time.Duration  `json:"duration"`
+	AllocBytes    uint64         `json:"alloc_bytes"`
+	NumSeries     int            `json:"num_series"`
+	NumChunks     int            `json:"num_chunks"`
+	CacheStats    CacheHitRatios `json:"cache_stats"`
+	MismatchedExp bool           `json:"mismatched_expected_series,omitempty"`
+}
+
+// CacheStatsFunc samples cumulative cache hit/request counts for the store
+// under test; it is called once before and once after every run of a case's
+// sub-benchmark (the testing package may run it several times with a growing
+// b.N), and the harness derives the run's incremental hit ratio from the two
+// samples.
+type CacheStatsFunc func() (CacheStats, error)
+
+// RunSeriesBenchmark runs each SeriesCase as a benchmark sub-test against
+// client, in both Go benchmark (via b.Run) and machine-readable form. The
+// JSON report is written to jsonOut if non-empty, so PRs can regress-check
+// query pushdown, lazy postings, and index-header changes without hand
+// copying numbers out of `go test -bench` output.
+func RunSeriesBenchmark(b *testing.B, client SeriesClient, cases []SeriesCase, statsFn CacheStatsFunc, jsonOut string) []CaseResult {
+	b.Helper()
+
+	results := make([]CaseResult, 0, len(cases))
+	for _, c := range cases {
+		c := c
+		// b.Run may invoke the sub-benchmark several times with a growing b.N;
+		// only the last (largest b.N) run's result is kept for the case.
+		var res CaseResult
+		b.Run(c.Name, func(b *testing.B) {
+			var memBefore, memAfter runtime.MemStats
+			var statsBefore, statsAfter CacheStats
+			var numSeries, numChunks int
+
+			runtime.ReadMemStats(&memBefore)
+			if statsFn != nil {
+				s, err := statsFn()
+				if err != nil {
+					b.Fatalf("sample cache stats before: %v", err)
+				}
+				statsBefore = s
+			}
+
+			start := time.Now()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ns, nc, err := client.Series(context.Background(), c.Req)
+				if err != nil {
+					b.Fatalf("series case %q: %v", c.Name, err)
+				}
+				numSeries, numChunks = ns, nc
+			}
+			b.StopTimer()
+			elapsed := time.Since(start)
+
+			runtime.ReadMemStats(&memAfter)
+			if statsFn != nil {
+				s, err := statsFn()
+				if err != nil {
+					b.Fatalf("sample cache stats after: %v", err)
+				}
+				statsAfter = s
+			}
+
+			res = CaseResult{
+				Name:          c.Name,
+				Duration:      elapsed / time.Duration(b.N),
+				AllocBytes:    (memAfter.TotalAlloc - memBefore.TotalAlloc) / uint64(b.N),
+				NumSeries:     numSeries,
+				NumChunks:     numChunks,
+				CacheStats:    ratioOf(statsAfter.Sub(statsBefore)),
+				MismatchedExp: numSeries != c.ExpectedSeries,
+			}
+			if res.MismatchedExp {
+				b.Logf("case %q: expected %d series, got %d", c.Name, c.ExpectedSeries, numSeries)
+			}
+		})
+		results = append(results, res)
+	}
+
+	if jsonOut != "" {
+		if err := writeJSONReport(jsonOut, results); err != nil {
+			b.Fatalf("write JSON report: %v", err)
+		}
+	}
+	return results
+}
+
+func writeJSONReport(path string, results []CaseResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "create report file")
+	}
+	defer func() { _ = f.Close() }()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
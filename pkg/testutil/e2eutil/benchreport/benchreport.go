@@ -0,0 +1,312 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package benchreport scrapes /metrics from the components of an e2ebench
+// topology once an interactive session ends and turns the result into a
+// Markdown + JSON report, optionally diffed against a previous run. It exists
+// so that query-pushdown, lazy-postings or sharding changes can be quantified
+// from a `go test` run instead of by hand-copying PromQL queries into a
+// browser.
+package benchreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Target is one component to scrape at report time, e.g. a querier, sidecar
+// or store gateway reachable at its external HTTP endpoint.
+type Target struct {
+	// Name identifies the component in the report, e.g. "query1" or "store1".
+	Name string
+	// MetricsURL is the full URL of the component's /metrics endpoint.
+	MetricsURL string
+}
+
+// Report is the machine-readable form written alongside the Markdown report.
+type Report struct {
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Components  map[string]ComponentMetrics `json:"components"`
+}
+
+// ComponentMetrics holds the subset of a component's metrics that benchreport
+// cares about: request latency quantiles, bucket store histograms, memory,
+// and gRPC client/server latencies between querier and stores.
+type ComponentMetrics struct {
+	// Quantiles maps a series key -- the metric name plus its full label set,
+	// e.g. `http_request_duration_seconds{handler="query_range"}` -- to its
+	// observed quantiles, keyed by quantile string ("0.5", "0.9", "0.99").
+	// Series are kept distinct rather than merged, since every metric family
+	// below carries label dimensions (method, handler, data_type, ...) that
+	// matter for the report.
+	Quantiles map[string]map[string]float64 `json:"quantiles,omitempty"`
+	// Counters maps the same kind of series key to a plain counter/gauge
+	// value, such as go_memstats_alloc_bytes.
+	Counters map[string]float64 `json:"counters,omitempty"`
+}
+
+// reportQuantiles are the quantiles computed for Histogram-typed metrics,
+// via linear interpolation over bucket boundaries (Summary-typed metrics
+// already carry their own pre-computed quantiles).
+var reportQuantiles = []float64{0.5, 0.9, 0.99}
+
+// interestingMetrics lists the metric families benchreport extracts from each
+// scrape; everything else is ignored to keep reports small and readable.
+var interestingMetrics = map[string]bool{
+	"http_request_duration_seconds":                    true,
+	"thanos_bucket_store_series_gate_duration_seconds": true,
+	"thanos_bucket_store_series_data_touched":          true,
+	"thanos_bucket_store_series_data_fetched":          true,
+	"thanos_bucket_store_series_result_series":         true,
+	"go_memstats_alloc_bytes":                          true,
+	"grpc_client_handling_seconds":                     true,
+	"grpc_server_handling_seconds":                     true,
+}
+
+// Collect scrapes every target and returns a Report covering all of them. A
+// target that fails to scrape is recorded with an empty ComponentMetrics
+// rather than aborting the whole report, since a crashed sidecar is itself
+// useful information.
+func Collect(targets []Target) (*Report, error) {
+	r := &Report{
+		GeneratedAt: time.Now(),
+		Components:  make(map[string]ComponentMetrics, len(targets)),
+	}
+	for _, tgt := range targets {
+		cm, err := scrapeOne(tgt.MetricsURL)
+		if err != nil {
+			r.Components[tgt.Name] = ComponentMetrics{}
+			continue
+		}
+		r.Components[tgt.Name] = cm
+	}
+	return r, nil
+}
+
+func scrapeOne(url string) (ComponentMetrics, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return ComponentMetrics{}, errors.Wrapf(err, "scrape %s", url)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return ComponentMetrics{}, errors.Wrapf(err, "parse metrics from %s", url)
+	}
+
+	cm := ComponentMetrics{
+		Quantiles: map[string]map[string]float64{},
+		Counters:  map[string]float64{},
+	}
+	for name, mf := range families {
+		if !interestingMetrics[name] {
+			continue
+		}
+		switch mf.GetType() {
+		case dto.MetricType_SUMMARY, dto.MetricType_HISTOGRAM:
+			quantilesOf(mf, cm.Quantiles)
+		default:
+			sumOf(mf, cm.Counters)
+		}
+	}
+	return cm, nil
+}
+
+// quantilesOf extracts quantiles for every labeled series in mf into out,
+// keyed by seriesKey so that series with different label values (method,
+// handler, data_type, ...) are kept distinct rather than overwriting one
+// another. Summary metrics already carry pre-computed quantiles; Histogram
+// metrics are interpolated from their bucket boundaries.
+func quantilesOf(mf *dto.MetricFamily, out map[string]map[string]float64) {
+	for _, m := range mf.GetMetric() {
+		key := seriesKey(mf.GetName(), m.GetLabel())
+		if s := m.GetSummary(); s != nil {
+			qs := make(map[string]float64, len(s.GetQuantile()))
+			for _, q := range s.GetQuantile() {
+				qs[fmt.Sprintf("%g", q.GetQuantile())] = q.GetValue()
+			}
+			out[key] = qs
+		}
+		if h := m.GetHistogram(); h != nil {
+			qs := make(map[string]float64, len(reportQuantiles))
+			for _, q := range reportQuantiles {
+				qs[fmt.Sprintf("%g", q)] = histogramQuantile(q, h)
+			}
+			out[key] = qs
+		}
+	}
+}
+
+// histogramQuantile estimates the q-th quantile of h by linear interpolation
+// between the cumulative bucket that first reaches the target rank and the
+// one before it, mirroring PromQL's histogram_quantile.
+func histogramQuantile(q float64, h *dto.Histogram) float64 {
+	buckets := h.GetBucket()
+	total := float64(h.GetSampleCount())
+	if len(buckets) == 0 || total == 0 {
+		return 0
+	}
+
+	rank := q * total
+	var prevCount, prevBound float64
+	for _, b := range buckets {
+		count := float64(b.GetCumulativeCount())
+		if count >= rank {
+			bound := b.GetUpperBound()
+			if count == prevCount {
+				return bound
+			}
+			return prevBound + (bound-prevBound)*(rank-prevCount)/(count-prevCount)
+		}
+		prevCount = count
+		prevBound = b.GetUpperBound()
+	}
+	return buckets[len(buckets)-1].GetUpperBound()
+}
+
+// sumOf extracts the value of every labeled series in mf into out, keyed by
+// seriesKey (see quantilesOf for why series are kept distinct).
+func sumOf(mf *dto.MetricFamily, out map[string]float64) {
+	for _, m := range mf.GetMetric() {
+		key := seriesKey(mf.GetName(), m.GetLabel())
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			out[key] = m.GetCounter().GetValue()
+		case dto.MetricType_GAUGE:
+			out[key] = m.GetGauge().GetValue()
+		}
+	}
+}
+
+// seriesKey renders a metric family name plus its label set as a single
+// string, e.g. `name{a="1",b="2"}`, with labels sorted for stable output.
+func seriesKey(name string, lbls []*dto.LabelPair) string {
+	if len(lbls) == 0 {
+		return name
+	}
+	pairs := make([]string, 0, len(lbls))
+	for _, l := range lbls {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+// WriteDir writes the report as both report.json and report.md into dir. If
+// baselinePath is non-empty, it is read as a previous report.json and a diff
+// section is appended to the Markdown, so a reviewer can see the delta
+// without re-running the baseline.
+func WriteDir(dir string, r *Report, baselinePath string) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return errors.Wrap(err, "create report dir")
+	}
+
+	jsonPath := filepath.Join(dir, "report.json")
+	f, err := os.Create(jsonPath)
+	if err != nil {
+		return errors.Wrap(err, "create report.json")
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	encErr := enc.Encode(r)
+	_ = f.Close()
+	if encErr != nil {
+		return errors.Wrap(encErr, "encode report.json")
+	}
+
+	md := toMarkdown(r)
+	if baselinePath != "" {
+		baseline, err := readReport(baselinePath)
+		if err != nil {
+			return errors.Wrap(err, "read baseline report")
+		}
+		md += "\n" + diffMarkdown(baseline, r)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "report.md"), []byte(md), 0640); err != nil {
+		return errors.Wrap(err, "write report.md")
+	}
+	return nil
+}
+
+func readReport(path string) (*Report, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func toMarkdown(r *Report) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# e2ebench report (%s)\n\n", r.GeneratedAt.Format(time.RFC3339))
+
+	for _, name := range sortedKeys(r.Components) {
+		cm := r.Components[name]
+		fmt.Fprintf(&sb, "## %s\n\n", name)
+
+		for _, metric := range sortedKeys(cm.Quantiles) {
+			fmt.Fprintf(&sb, "- `%s`:", metric)
+			for _, q := range sortedKeys(cm.Quantiles[metric]) {
+				fmt.Fprintf(&sb, " %s=%.4f", q, cm.Quantiles[metric][q])
+			}
+			sb.WriteString("\n")
+		}
+		for _, metric := range sortedKeys(cm.Counters) {
+			fmt.Fprintf(&sb, "- `%s`: %.0f\n", metric, cm.Counters[metric])
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func diffMarkdown(baseline, current *Report) string {
+	var sb strings.Builder
+	sb.WriteString("## Diff vs baseline\n\n")
+
+	for _, name := range sortedKeys(current.Components) {
+		baseCm, ok := baseline.Components[name]
+		if !ok {
+			continue
+		}
+		curCm := current.Components[name]
+		fmt.Fprintf(&sb, "### %s\n\n", name)
+		for _, metric := range sortedKeys(curCm.Counters) {
+			oldV, newV := baseCm.Counters[metric], curCm.Counters[metric]
+			fmt.Fprintf(&sb, "- `%s`: %.0f -> %.0f (%+.1f%%)\n", metric, oldV, newV, pctDelta(oldV, newV))
+		}
+	}
+	return sb.String()
+}
+
+func pctDelta(old, new float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return (new - old) / old * 100
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+// Package blockgen generates continuous TSDB blocks in pure Go, so that e2e
+// tests and benchmarks can build reproducible sample corpora without
+// depending on Docker or the thanosbench image.
+//
+// It mirrors the shape of thanosbench's "continuous" block plan: a fixed
+// number of series sharing a metric name prefix, split into consecutive,
+// non-overlapping blocks of a given duration, sampled at a fixed interval.
+// Block writing itself is delegated to e2eutil.CreateBlock, the same helper
+// receive/compact/downsample e2e tests already use, so all of them build
+// their corpora through one reusable generator.
+package blockgen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/thanos-io/thanos/pkg/block/metadata"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil"
+)
+
+// SeriesSpec describes one dimension of series to generate. NumSeries copies
+// of a series are created, each with Labels plus an index label so that the
+// resulting series are distinct.
+type SeriesSpec struct {
+	// MetricPrefix is used as the `__name__` prefix, e.g. "continuous_app_metric"
+	// produces continuous_app_metric0, continuous_app_metric1, ...
+	MetricPrefix string
+	// NumSeries is the number of distinct series to generate for this spec.
+	NumSeries int
+	// Labels are extra, constant labels added to every series (in addition to
+	// `__name__` and the generated index label).
+	Labels labels.Labels
+}
+
+// Spec configures a full continuous corpus: one or more SeriesSpecs, written
+// out as consecutive blocks covering [MinTime, MaxTime).
+type Spec struct {
+	Series []SeriesSpec
+
+	// ExtLset is attached to every produced block's meta.json as the Thanos
+	// external labels (e.g. cluster/replica), mimicking sidecar-uploaded blocks.
+	ExtLset labels.Labels
+
+	// MinTime and MaxTime bound the generated range.
+	MinTime, MaxTime time.Time
+	// BlockDuration is the size of each contiguous block, e.g. 2h to mimic
+	// Prometheus' default, or 24h for already-compacted-looking corpora.
+	BlockDuration time.Duration
+	// SampleInterval is the spacing between samples of a single series.
+	SampleInterval time.Duration
+}
+
+// Generate writes continuous blocks for spec into dir, one sub-directory per
+// block named by its ULID, and returns the generated block IDs in time order.
+func Generate(ctx context.Context, dir string, spec Spec) ([]ulid.ULID, error) {
+	if spec.BlockDuration <= 0 {
+		return nil, errors.New("blockgen: BlockDuration must be positive")
+	}
+	if spec.SampleInterval <= 0 {
+		return nil, errors.New("blockgen: SampleInterval must be positive")
+	}
+	if !spec.MaxTime.After(spec.MinTime) {
+		return nil, errors.New("blockgen: MaxTime must be after MinTime")
+	}
+
+	series := expandSeries(spec.Series)
+	stepMs := spec.SampleInterval.Milliseconds()
+
+	var ids []ulid.ULID
+	for blockMinT := spec.MinTime; blockMinT.Before(spec.MaxTime); blockMinT = blockMinT.Add(spec.BlockDuration) {
+		blockMaxT := blockMinT.Add(spec.BlockDuration)
+		if blockMaxT.After(spec.MaxTime) {
+			blockMaxT = spec.MaxTime
+		}
+
+		mint, maxt := timeToMs(blockMinT), timeToMs(blockMaxT)
+		numSamples := int((maxt - mint) / stepMs)
+		if numSamples == 0 {
+			continue
+		}
+
+		id, err := e2eutil.CreateBlock(ctx, dir, series, numSamples, mint, maxt, spec.ExtLset, 0, metadata.NoneFunc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "blockgen: create block [%s, %s)", blockMinT, blockMaxT)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// expandSeries materializes the NumSeries copies of each SeriesSpec into
+// concrete label sets, e.g. {__name__="continuous_app_metric0", series="0", ...Labels}.
+func expandSeries(specs []SeriesSpec) []labels.Labels {
+	var out []labels.Labels
+	for _, s := range specs {
+		for i := 0; i < s.NumSeries; i++ {
+			b := labels.NewBuilder(s.Labels)
+			b.Set(labels.MetricName, fmt.Sprintf("%s%d", s.MetricPrefix, i))
+			b.Set("series", fmt.Sprintf("%d", i))
+			out = append(out, b.Labels())
+		}
+	}
+	return out
+}
+
+func timeToMs(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
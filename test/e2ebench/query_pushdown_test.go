@@ -4,27 +4,39 @@
 package e2ebench_test
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 	execlib "os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/efficientgo/e2e"
 	e2edb "github.com/efficientgo/e2e/db"
 	e2einteractive "github.com/efficientgo/e2e/interactive"
 	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/thanos-io/thanos/pkg/objstore/client"
 	"github.com/thanos-io/thanos/pkg/objstore/s3"
 	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil/benchreport"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil/blockgen"
 	"gopkg.in/yaml.v2"
 )
 
+// baseline points at a previous benchreport report.json to diff the run
+// against, e.g. `go test -run TestQueryPushdown_Demo -baseline prev/report.json`
+// with prev/report.json a copy of an earlier run's data/report-sidecar/report.json
+// (each run overwrites that file before the baseline is read).
+var baseline = flag.String("baseline", "", "path to a previous benchreport report.json to diff this run against")
+
 const data = "data"
 
 var (
-	maxTimeFresh = `2021-07-27T00:00:00Z`
-	maxTimeOld   = `2021-07-20T00:00:00Z`
+	maxTimeFresh = time.Date(2021, 7, 27, 0, 0, 0, 0, time.UTC)
+	maxTimeOld   = time.Date(2021, 7, 20, 0, 0, 0, 0, time.UTC)
 
 	store1Data = func() string { a, _ := filepath.Abs(filepath.Join(data, "store1")); return a }()
 	store2Data = func() string { a, _ := filepath.Abs(filepath.Join(data, "store2")); return a }()
@@ -39,6 +51,32 @@ func exec(cmd string, args ...string) error {
 	return nil
 }
 
+// continuousSpec returns the blockgen.Spec that reproduces thanosbench's
+// "continuous-1w-small" profile: 10k series split into 1w of 2h blocks.
+func continuousSpec(dataDir string, extLset labels.Labels, maxTime time.Time) blockgen.Spec {
+	return blockgen.Spec{
+		Series: []blockgen.SeriesSpec{
+			{MetricPrefix: "continuous_app_metric", NumSeries: 10000, Labels: labels.EmptyLabels()},
+		},
+		ExtLset:        extLset,
+		MinTime:        maxTime.Add(-7 * 24 * time.Hour),
+		MaxTime:        maxTime,
+		BlockDuration:  2 * time.Hour,
+		SampleInterval: 15 * time.Second,
+	}
+}
+
+// collectReport scrapes targets, writes the resulting report as
+// report.json/report.md under filepath.Join(data, subdir), optionally
+// diffing it against -baseline, and is meant to be called once an
+// e2ebench demo's interactive session ends.
+func collectReport(t *testing.T, subdir string, targets []benchreport.Target) {
+	t.Helper()
+	report, err := benchreport.Collect(targets)
+	testutil.Ok(t, err)
+	testutil.Ok(t, benchreport.WriteDir(filepath.Join(data, subdir), report, *baseline))
+}
+
 func createData() (perr error) {
 	fmt.Println("Re-creating data (can take minutes)...")
 	defer func() {
@@ -47,38 +85,19 @@ func createData() (perr error) {
 		}
 	}()
 
-	if err := exec(
-		"sh", "-c",
-		fmt.Sprintf("mkdir -p %s && "+
-			"docker run -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block plan -p continuous-1w-small --labels 'cluster=\"eu-1\"' --labels 'replica=\"0\"' --max-time=%s | "+
-			"docker run -v %s/:/shared -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block gen --output.dir /shared", store1Data, maxTimeOld, store1Data),
-	); err != nil {
-		return err
+	ctx := context.Background()
+
+	if _, err := blockgen.Generate(ctx, store1Data, continuousSpec(store1Data, labels.FromStrings("cluster", "eu-1", "replica", "0"), maxTimeOld)); err != nil {
+		return errors.Wrap(err, "generate store1 blocks")
 	}
-	if err := exec(
-		"sh", "-c",
-		fmt.Sprintf("mkdir -p %s && "+
-			"docker run -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block plan -p continuous-1w-small --labels 'cluster=\"us-1\"' --labels 'replica=\"0\"' --max-time=%s | "+
-			"docker run -v %s/:/shared -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block gen --output.dir /shared", store2Data, maxTimeOld, store2Data),
-	); err != nil {
-		return err
+	if _, err := blockgen.Generate(ctx, store2Data, continuousSpec(store2Data, labels.FromStrings("cluster", "us-1", "replica", "0"), maxTimeOld)); err != nil {
+		return errors.Wrap(err, "generate store2 blocks")
 	}
-
-	if err := exec(
-		"sh", "-c",
-		fmt.Sprintf("mkdir -p %s && "+
-			"docker run -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block plan -p continuous-1w-small --max-time=%s | "+
-			"docker run -v %s/:/shared -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block gen --output.dir /shared", prom1Data, maxTimeFresh, prom1Data),
-	); err != nil {
-		return err
+	if _, err := blockgen.Generate(ctx, prom1Data, continuousSpec(prom1Data, labels.EmptyLabels(), maxTimeFresh)); err != nil {
+		return errors.Wrap(err, "generate prom1 blocks")
 	}
-	if err := exec(
-		"sh", "-c",
-		fmt.Sprintf("mkdir -p %s && "+
-			"docker run -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block plan -p continuous-1w-small --max-time=%s | "+
-			"docker run -v %s/:/shared -i quay.io/thanos/thanosbench:v0.2.0-rc.1 block gen --output.dir /shared", prom2Data, maxTimeFresh, prom2Data),
-	); err != nil {
-		return err
+	if _, err := blockgen.Generate(ctx, prom2Data, continuousSpec(prom2Data, labels.EmptyLabels(), maxTimeFresh)); err != nil {
+		return errors.Wrap(err, "generate prom2 blocks")
 	}
 	return nil
 }
@@ -272,4 +291,16 @@ global:
 
 	testutil.Ok(t, e2einteractive.OpenInBrowser(fmt.Sprintf("http://%s/%s", query1.Endpoint("http"), "graph?g0.expr=count(%7B__name__%3D~\"continuous_app_metric99\"%7D)%20by%20(replica)&g0.tab=0&g0.stacked=0&g0.range_input=2w&g0.max_source_resolution=0s&g0.deduplicate=0&g0.partial_response=0&g0.store_matches=%5B%5D&g0.end_input=2021-07-27%2000%3A00%3A00")))
 	testutil.Ok(t, e2einteractive.RunUntilEndpointHit())
-}
\ No newline at end of file
+
+	// Now that the interactive session is done, scrape every component once
+	// more and write a report so the run can be compared against -baseline
+	// without hand-copying PromQL queries into a browser.
+	collectReport(t, "report-sidecar", []benchreport.Target{
+		{Name: "query1", MetricsURL: fmt.Sprintf("http://%s/metrics", query1.Endpoint("http"))},
+		{Name: "store1", MetricsURL: fmt.Sprintf("http://%s/metrics", store1.Endpoint("http"))},
+		{Name: "store2", MetricsURL: fmt.Sprintf("http://%s/metrics", store2.Endpoint("http"))},
+		{Name: "sidecar-prom-ha0", MetricsURL: fmt.Sprintf("http://%s/metrics", sidecarHA0.Endpoint("http"))},
+		{Name: "sidecar-prom-ha1", MetricsURL: fmt.Sprintf("http://%s/metrics", sidecarHA1.Endpoint("http"))},
+		{Name: "sidecar2", MetricsURL: fmt.Sprintf("http://%s/metrics", sidecar2.Endpoint("http"))},
+	})
+}
@@ -0,0 +1,259 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package e2ebench_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/e2e"
+	e2edb "github.com/efficientgo/e2e/db"
+	e2einteractive "github.com/efficientgo/e2e/interactive"
+	"github.com/thanos-io/thanos/pkg/objstore/client"
+	"github.com/thanos-io/thanos/pkg/objstore/s3"
+	"github.com/thanos-io/thanos/pkg/testutil"
+	"github.com/thanos-io/thanos/pkg/testutil/e2eutil/benchreport"
+	"gopkg.in/yaml.v2"
+)
+
+// Test args: -test.timeout 9999m
+//
+// TestQueryPushdown_Receive_Demo mirrors TestQueryPushdown_Demo's corpus and
+// topology shape, but replaces the sidecar-fed Prometheus replicas with a
+// Thanos Receive hashring and its own store gateway. Remote-write only
+// streams newly-scraped samples out of a WAL, and blockgen's pre-generated
+// blocks never have one, so there is no way to feed the receiver the same
+// corpus through its ingestion path the way the sidecar demo does through
+// Prometheus's data dir; the prom1Data/prom2Data corpus is instead written
+// straight into the receiver's bucket. This demo is therefore a comparison
+// of the receive store gateway's read path against TestQueryPushdown_Demo's
+// sidecars serving the same corpus, not of the remote-write ingestion path.
+func TestQueryPushdown_Receive_Demo(t *testing.T) {
+	_, err := os.Stat(data)
+	if os.IsNotExist(err) {
+		testutil.Ok(t, createData())
+	} else {
+		testutil.Ok(t, err)
+	}
+
+	e, err := e2e.NewDockerEnvironment("query_pushdown_receive_demo")
+	testutil.Ok(t, err)
+	t.Cleanup(e.Close)
+
+	// Object storage holding the long-term (store1/store2) buckets, same as
+	// the sidecar demo, plus a third bucket configured as the receiver's own.
+	m1 := e2edb.NewMinio(e, "minio-1", "default")
+	testutil.Ok(t, exec("cp", "-r", store1Data, filepath.Join(m1.Dir(), "bkt1")))
+	testutil.Ok(t, exec("cp", "-r", store2Data, filepath.Join(m1.Dir(), "bkt2")))
+
+	bkt1Config, err := yaml.Marshal(client.BucketConfig{
+		Type: client.S3,
+		Config: s3.Config{
+			Bucket:    "bkt1",
+			AccessKey: e2edb.MinioAccessKey,
+			SecretKey: e2edb.MinioSecretKey,
+			Endpoint:  m1.InternalEndpoint("http"),
+			Insecure:  true,
+		},
+	})
+	testutil.Ok(t, err)
+	store1 := e2edb.NewThanosStore(e, "store1", bkt1Config, e2edb.WithImage("thanos:latest"))
+
+	bkt2Config, err := yaml.Marshal(client.BucketConfig{
+		Type: client.S3,
+		Config: s3.Config{
+			Bucket:    "bkt2",
+			AccessKey: e2edb.MinioAccessKey,
+			SecretKey: e2edb.MinioSecretKey,
+			Endpoint:  m1.InternalEndpoint("http"),
+			Insecure:  true,
+		},
+	})
+	testutil.Ok(t, err)
+	store2 := e2edb.NewThanosStore(e, "store2", bkt2Config, e2edb.WithImage("thanos:latest"))
+
+	recvBktConfig, err := yaml.Marshal(client.BucketConfig{
+		Type: client.S3,
+		Config: s3.Config{
+			Bucket:    "bkt-receive",
+			AccessKey: e2edb.MinioAccessKey,
+			SecretKey: e2edb.MinioSecretKey,
+			Endpoint:  m1.InternalEndpoint("http"),
+			Insecure:  true,
+		},
+	})
+	testutil.Ok(t, err)
+
+	// Single-node hashring; a real one would shard across several receivers,
+	// but this demo never remote-writes into it (see doc comment above), so
+	// it stays idle and exists only to show where it sits in the topology.
+	receive := e2edb.NewThanosReceive(e, "receive1", recvBktConfig, e2edb.WithImage("thanos:latest"))
+	testutil.Ok(t, receive.SetHashring([]e2edb.HashringConfig{{Endpoints: []string{receive.InternalEndpoint("grpc")}}}))
+
+	receiveStore := e2edb.NewThanosStore(e, "receive-store", recvBktConfig, e2edb.WithImage("thanos:latest"))
+
+	// Seed the receive bucket directly with the same prom1Data/prom2Data
+	// blocks the sidecar demo serves via its Prometheus+sidecar pairs, since
+	// those blocks have no WAL for remote-write to replay.
+	recvData := filepath.Join(m1.Dir(), "bkt-receive")
+	testutil.Ok(t, os.MkdirAll(recvData, 0750))
+	testutil.Ok(t, exec("sh", "-c", "cp -r "+prom1Data+"/. "+recvData))
+	testutil.Ok(t, exec("sh", "-c", "cp -r "+prom2Data+"/. "+recvData))
+
+	testutil.Ok(t, e2e.StartAndWaitReady(m1))
+	testutil.Ok(t, e2e.StartAndWaitReady(receive, receiveStore, store1, store2))
+
+	query1 := e2edb.NewThanosQuerier(e, "query1", []string{
+		store1.InternalEndpoint("grpc"),
+		store2.InternalEndpoint("grpc"),
+		receiveStore.InternalEndpoint("grpc"),
+		receive.InternalEndpoint("grpc"),
+	})
+	testutil.Ok(t, e2e.StartAndWaitReady(query1))
+	testutil.Ok(t, query1.WaitSumMetricsWithOptions(e2e.Equals(4), []string{"thanos_store_nodes_grpc_connections"}, e2e.WaitMissingMetrics()))
+
+	testutil.Ok(t, e2einteractive.OpenInBrowser(fmt.Sprintf("http://%s/%s", query1.Endpoint("http"), "graph?g0.expr=count(%7B__name__%3D~\"continuous_app_metric99\"%7D)%20by%20(replica)&g0.tab=0&g0.stacked=0&g0.range_input=2w&g0.max_source_resolution=0s&g0.deduplicate=0&g0.partial_response=0&g0.store_matches=%5B%5D&g0.end_input=2021-07-27%2000%3A00%3A00")))
+	testutil.Ok(t, e2einteractive.RunUntilEndpointHit())
+
+	collectReport(t, "report-receive", []benchreport.Target{
+		{Name: "query1", MetricsURL: fmt.Sprintf("http://%s/metrics", query1.Endpoint("http"))},
+		{Name: "store1", MetricsURL: fmt.Sprintf("http://%s/metrics", store1.Endpoint("http"))},
+		{Name: "store2", MetricsURL: fmt.Sprintf("http://%s/metrics", store2.Endpoint("http"))},
+		{Name: "receive-store", MetricsURL: fmt.Sprintf("http://%s/metrics", receiveStore.Endpoint("http"))},
+		{Name: "receive1", MetricsURL: fmt.Sprintf("http://%s/metrics", receive.Endpoint("http"))},
+	})
+}
+
+// Test args: -test.timeout 9999m
+//
+// TestQueryPushdown_QueryFrontend_Demo reuses the sidecar+store-gateway
+// topology from TestQueryPushdown_Demo but puts two Query Frontends in front
+// of the same querier, one backed by an in-memory results cache and one by
+// memcached (via e2edb.NewMemcached), both with the same split-by-interval
+// config, so the two caching strategies can be compared side by side against
+// the plain querier demo using the same corpus.
+func TestQueryPushdown_QueryFrontend_Demo(t *testing.T) {
+	_, err := os.Stat(data)
+	if os.IsNotExist(err) {
+		testutil.Ok(t, createData())
+	} else {
+		testutil.Ok(t, err)
+	}
+
+	e, err := e2e.NewDockerEnvironment("query_pushdown_frontend_demo")
+	testutil.Ok(t, err)
+	t.Cleanup(e.Close)
+
+	m1 := e2edb.NewMinio(e, "minio-1", "default")
+	testutil.Ok(t, exec("cp", "-r", store1Data, filepath.Join(m1.Dir(), "bkt1")))
+	testutil.Ok(t, exec("cp", "-r", store2Data, filepath.Join(m1.Dir(), "bkt2")))
+
+	bkt1Config, err := yaml.Marshal(client.BucketConfig{
+		Type: client.S3,
+		Config: s3.Config{
+			Bucket:    "bkt1",
+			AccessKey: e2edb.MinioAccessKey,
+			SecretKey: e2edb.MinioSecretKey,
+			Endpoint:  m1.InternalEndpoint("http"),
+			Insecure:  true,
+		},
+	})
+	testutil.Ok(t, err)
+	store1 := e2edb.NewThanosStore(e, "store1", bkt1Config, e2edb.WithImage("thanos:latest"))
+
+	bkt2Config, err := yaml.Marshal(client.BucketConfig{
+		Type: client.S3,
+		Config: s3.Config{
+			Bucket:    "bkt2",
+			AccessKey: e2edb.MinioAccessKey,
+			SecretKey: e2edb.MinioSecretKey,
+			Endpoint:  m1.InternalEndpoint("http"),
+			Insecure:  true,
+		},
+	})
+	testutil.Ok(t, err)
+	store2 := e2edb.NewThanosStore(e, "store2", bkt2Config, e2edb.WithImage("thanos:latest"))
+
+	promHA0 := e2edb.NewPrometheus(e, "prom-ha0")
+	promHA1 := e2edb.NewPrometheus(e, "prom-ha1")
+	prom2 := e2edb.NewPrometheus(e, "prom2")
+
+	sidecarHA0 := e2edb.NewThanosSidecar(e, "sidecar-prom-ha0", promHA0, e2edb.WithImage("thanos:latest"))
+	sidecarHA1 := e2edb.NewThanosSidecar(e, "sidecar-prom-ha1", promHA1, e2edb.WithImage("thanos:latest"))
+	sidecar2 := e2edb.NewThanosSidecar(e, "sidecar2", prom2, e2edb.WithImage("thanos:latest"))
+
+	testutil.Ok(t, exec("cp", "-r", prom1Data, promHA0.Dir()))
+	testutil.Ok(t, exec("sh", "-c", "find "+prom1Data+" -maxdepth 1 -type d | tail -5 | xargs cp -r -t "+promHA1.Dir()))
+	testutil.Ok(t, exec("cp", "-r", prom2Data, prom2.Dir()))
+
+	testutil.Ok(t, promHA0.SetConfig(`
+global:
+  external_labels:
+    cluster: eu-1
+    replica: 0
+`))
+	testutil.Ok(t, promHA1.SetConfig(`
+global:
+  external_labels:
+    cluster: eu-1
+    replica: 1
+`))
+	testutil.Ok(t, prom2.SetConfig(`
+global:
+  external_labels:
+    cluster: us-1
+    replica: 0
+`))
+
+	testutil.Ok(t, e2e.StartAndWaitReady(m1))
+	testutil.Ok(t, e2e.StartAndWaitReady(promHA0, promHA1, prom2, sidecarHA0, sidecarHA1, sidecar2, store1, store2))
+
+	query1 := e2edb.NewThanosQuerier(e, "query1", []string{
+		store1.InternalEndpoint("grpc"),
+		store2.InternalEndpoint("grpc"),
+		sidecarHA0.InternalEndpoint("grpc"),
+		sidecarHA1.InternalEndpoint("grpc"),
+		sidecar2.InternalEndpoint("grpc"),
+	})
+	testutil.Ok(t, e2e.StartAndWaitReady(query1))
+	testutil.Ok(t, query1.WaitSumMetricsWithOptions(e2e.Equals(5), []string{"thanos_store_nodes_grpc_connections"}, e2e.WaitMissingMetrics()))
+
+	memcached := e2edb.NewMemcached(e, "memcached")
+	testutil.Ok(t, e2e.StartAndWaitReady(memcached))
+
+	frontendMemcached := e2edb.NewThanosQueryFrontend(e, "query-frontend-memcached", query1.InternalEndpoint("http"), e2edb.WithImage("thanos:latest"), e2edb.WithFlagOverride(map[string]string{
+		"--query-range.split-interval":        "24h",
+		"--query-frontend.compress-responses": "true",
+		"--cache-compression-type":            "snappy",
+		"--query-range.response-cache-config": fmt.Sprintf(`type: MEMCACHED
+config:
+  addresses: [%s]`, memcached.InternalEndpoint("memcached")),
+	}))
+	frontendInMemory := e2edb.NewThanosQueryFrontend(e, "query-frontend-inmemory", query1.InternalEndpoint("http"), e2edb.WithImage("thanos:latest"), e2edb.WithFlagOverride(map[string]string{
+		"--query-range.split-interval":        "24h",
+		"--query-frontend.compress-responses": "true",
+		"--query-range.response-cache-config": `type: IN-MEMORY
+config:
+  max_size: 1GB`,
+	}))
+	testutil.Ok(t, e2e.StartAndWaitReady(frontendMemcached, frontendInMemory))
+
+	const query = "graph?g0.expr=count(%7B__name__%3D~\"continuous_app_metric99\"%7D)%20by%20(replica)&g0.tab=0&g0.stacked=0&g0.range_input=2w&g0.max_source_resolution=0s&g0.deduplicate=0&g0.partial_response=0&g0.store_matches=%5B%5D&g0.end_input=2021-07-27%2000%3A00%3A00"
+	testutil.Ok(t, e2einteractive.OpenInBrowser(fmt.Sprintf("http://%s/%s", frontendInMemory.Endpoint("http"), query)))
+	testutil.Ok(t, e2einteractive.OpenInBrowser(fmt.Sprintf("http://%s/%s", frontendMemcached.Endpoint("http"), query)))
+	testutil.Ok(t, e2einteractive.RunUntilEndpointHit())
+
+	collectReport(t, "report-frontend", []benchreport.Target{
+		{Name: "query1", MetricsURL: fmt.Sprintf("http://%s/metrics", query1.Endpoint("http"))},
+		{Name: "query-frontend-memcached", MetricsURL: fmt.Sprintf("http://%s/metrics", frontendMemcached.Endpoint("http"))},
+		{Name: "query-frontend-inmemory", MetricsURL: fmt.Sprintf("http://%s/metrics", frontendInMemory.Endpoint("http"))},
+		{Name: "store1", MetricsURL: fmt.Sprintf("http://%s/metrics", store1.Endpoint("http"))},
+		{Name: "store2", MetricsURL: fmt.Sprintf("http://%s/metrics", store2.Endpoint("http"))},
+		{Name: "sidecar-prom-ha0", MetricsURL: fmt.Sprintf("http://%s/metrics", sidecarHA0.Endpoint("http"))},
+		{Name: "sidecar-prom-ha1", MetricsURL: fmt.Sprintf("http://%s/metrics", sidecarHA1.Endpoint("http"))},
+		{Name: "sidecar2", MetricsURL: fmt.Sprintf("http://%s/metrics", sidecar2.Endpoint("http"))},
+	})
+}